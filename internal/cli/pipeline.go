@@ -28,6 +28,7 @@ with dependencies, conditionals, and variable substitution.
 
 Subcommands:
   run      Run a workflow from a YAML/TOML file
+  validate Check a workflow file for errors without running it
   resume   Resume a workflow from saved state
   status   Check the status of a running pipeline
   list     List all tracked pipelines
@@ -44,6 +45,9 @@ Examples:
   # Run with variables
   ntm pipeline run workflow.yaml --session proj --var env=prod --var debug=true
 
+  # Validate a workflow file
+  ntm pipeline validate workflow.yaml
+
   # Check status
   ntm pipeline status run-20241230-123456-abcd
 
@@ -62,6 +66,7 @@ Examples:
 
 	cmd.AddCommand(
 		newPipelineRunCmd(),
+		newPipelineValidateCmd(),
 		newPipelineStatusCmd(),
 		newPipelineListCmd(),
 		newPipelineCancelCmd(),
@@ -191,10 +196,8 @@ Examples:
 			if !result.Valid {
 				fmt.Fprintln(os.Stderr, "Validation failed:")
 				for _, e := range result.Errors {
-					fmt.Printf("  ❌ %s\n", e.Message)
-					if e.Hint != "" {
-						fmt.Printf("     💡 %s\n", e.Hint)
-					}
+					fmt.Printf("  ❌ %s\n", e.Error())
+					printValidationHint(e.Hint)
 				}
 				return fmt.Errorf("workflow validation failed")
 			}
@@ -301,6 +304,67 @@ Examples:
 	return cmd
 }
 
+// newPipelineValidateCmd creates the "pipeline validate" subcommand
+func newPipelineValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate <workflow-file>",
+		Short: "Validate a workflow file",
+		Long: `Parse and validate a workflow file without running it.
+
+Reports schema errors, dependency cycles, unknown variable references and
+syntax errors in when/while expressions (with the column of the error).
+
+Examples:
+  ntm pipeline validate workflow.yaml
+  ntm pipeline validate workflow.yaml --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workflow, result, err := pipeline.LoadAndValidate(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to load workflow: %w", err)
+			}
+
+			if jsonOutput {
+				if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+					return err
+				}
+				if !result.Valid {
+					return fmt.Errorf("workflow validation failed")
+				}
+				return nil
+			}
+
+			for _, e := range result.Errors {
+				fmt.Printf("  ❌ %s\n", e.Error())
+				printValidationHint(e.Hint)
+			}
+			for _, w := range result.Warnings {
+				fmt.Printf("  ⚠️  %s\n", w.Error())
+				printValidationHint(w.Hint)
+			}
+
+			if !result.Valid {
+				return fmt.Errorf("workflow validation failed (%d errors)", len(result.Errors))
+			}
+
+			output.SuccessCheck(fmt.Sprintf("Workflow %s is valid (%d steps)", workflow.Name, len(workflow.Steps)))
+			return nil
+		},
+	}
+}
+
+// printValidationHint prints a (possibly multi-line) validation hint.
+func printValidationHint(hint string) {
+	if hint == "" {
+		return
+	}
+	lines := strings.Split(hint, "\n")
+	fmt.Printf("     💡 %s\n", lines[0])
+	for _, line := range lines[1:] {
+		fmt.Printf("        %s\n", line)
+	}
+}
+
 // newPipelineStatusCmd creates the "pipeline status" subcommand
 func newPipelineStatusCmd() *cobra.Command {
 	var watch bool
@@ -491,10 +555,8 @@ Examples:
 				}
 				fmt.Fprintln(os.Stderr, "Validation failed:")
 				for _, e := range result.Errors {
-					fmt.Printf("  ❌ %s\n", e.Message)
-					if e.Hint != "" {
-						fmt.Printf("     💡 %s\n", e.Hint)
-					}
+					fmt.Printf("  ❌ %s\n", e.Error())
+					printValidationHint(e.Hint)
 				}
 				return fmt.Errorf("workflow validation failed")
 			}
@@ -331,58 +331,6 @@ func TestEvaluateCondition_BackwardCompatibility(t *testing.T) {
 	}
 }
 
-func TestCleanValue(t *testing.T) {
-	tests := []struct {
-		input string
-		want  string
-	}{
-		{`"quoted"`, "quoted"},
-		{`'single'`, "single"},
-		{"  spaces  ", "spaces"},
-		{`"with spaces"`, "with spaces"},
-		{"unquoted", "unquoted"},
-		{`""`, ""},
-		{`''`, ""},
-	}
-
-	for _, tt := range tests {
-		got := cleanValue(tt.input)
-		if got != tt.want {
-			t.Errorf("cleanValue(%q) = %q, want %q", tt.input, got, tt.want)
-		}
-	}
-}
-
-func TestParseNumericPair(t *testing.T) {
-	tests := []struct {
-		left    string
-		right   string
-		wantL   float64
-		wantR   float64
-		wantErr bool
-	}{
-		{"10", "20", 10, 20, false},
-		{"10.5", "20.5", 10.5, 20.5, false},
-		{"-5", "5", -5, 5, false},
-		{`"10"`, "20", 10, 20, false}, // quoted number
-		{"abc", "20", 0, 0, true},
-		{"10", "xyz", 0, 0, true},
-	}
-
-	for _, tt := range tests {
-		gotL, gotR, err := parseNumericPair(tt.left, tt.right)
-		if (err != nil) != tt.wantErr {
-			t.Errorf("parseNumericPair(%q, %q) error = %v, wantErr %v", tt.left, tt.right, err, tt.wantErr)
-			continue
-		}
-		if !tt.wantErr {
-			if gotL != tt.wantL || gotR != tt.wantR {
-				t.Errorf("parseNumericPair(%q, %q) = (%v, %v), want (%v, %v)", tt.left, tt.right, gotL, gotR, tt.wantL, tt.wantR)
-			}
-		}
-	}
-}
-
 func TestValidateCondition(t *testing.T) {
 	tests := []struct {
 		condition  string
@@ -407,27 +355,6 @@ func TestValidateCondition(t *testing.T) {
 	}
 }
 
-func TestFindLogicalOp(t *testing.T) {
-	tests := []struct {
-		expr string
-		op   string
-		want int
-	}{
-		{"a AND b", " AND ", 1},
-		{"a OR b", " OR ", 1},
-		{`"a AND b" OR c`, " OR ", 9}, // AND inside quotes should be ignored, " OR " starts at 9
-		{"(a AND b) OR c", " OR ", 9}, // AND inside parens should be ignored, " OR " starts at 9
-		{"no operators", " AND ", -1},
-	}
-
-	for _, tt := range tests {
-		got := findLogicalOp(tt.expr, tt.op)
-		if got != tt.want {
-			t.Errorf("findLogicalOp(%q, %q) = %d, want %d", tt.expr, tt.op, got, tt.want)
-		}
-	}
-}
-
 func TestConditionEvaluator_WithStepOutputs(t *testing.T) {
 	state := &ExecutionState{
 		Variables: map[string]interface{}{
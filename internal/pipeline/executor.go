@@ -1325,8 +1325,10 @@ func (e *Executor) substituteVariables(s string) string {
 // - Boolean truthy check
 // - Equality operators (==, !=)
 // - Comparison operators (>, <, >=, <=)
-// - Contains operator (contains)
-// - Logical operators (AND, OR, NOT)
+// - Contains, matches and in operators
+// - Logical operators (&&, ||, !, AND, OR, NOT) with parentheses
+// - Field and index access into parsed output (steps.id.data.items[0])
+// - Functions: len, contains, matches, startsWith, endsWith
 // - Type coercion for numeric comparisons
 // Thread-safe: acquires read lock on Variables for concurrent access during parallel execution.
 func (e *Executor) evaluateCondition(condition string) (bool, error) {
@@ -0,0 +1,1092 @@
+// Package pipeline provides workflow execution for AI agent orchestration.
+// expr.go implements the expression language used by step 'when' conditions
+// and loop 'while' conditions.
+//
+// Grammar (lowest to highest precedence):
+//
+//	expr       = or
+//	or         = and { ("||" | "OR") and }
+//	and        = unary { ("&&" | "AND") unary }
+//	unary      = ("!" | "NOT") unary | comparison
+//	comparison = postfix [ op postfix ]    op: == != < <= > >= contains matches in
+//	postfix    = primary { "." ident [ "(" args ")" ] | "[" expr "]" }
+//	primary    = number | string | ${ref} | ident | ident "(" args ")" |
+//	             "(" expr ")" | "[" [ expr { "," expr } ] "]"
+//
+// Variable references (${vars.x}, ${steps.id.output}, ...) resolve to typed
+// values rather than being spliced into the expression text, so values that
+// contain operators or quotes cannot change the meaning of a condition.
+// Namespaced paths may also be written without ${}: vars.x, steps.id.data.
+// Unquoted words that are not keywords, namespaces or functions are treated
+// as string literals, which keeps legacy conditions like "${vars.env} == prod"
+// working.
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ExprError is a syntax or evaluation error in a condition expression.
+// Pos is the 0-based byte offset of the offending token in Expr.
+type ExprError struct {
+	Expr    string
+	Pos     int
+	Message string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column(), e.Message)
+}
+
+// Column returns the 1-based column of the error.
+func (e *ExprError) Column() int {
+	if e.Pos < 0 {
+		return 1
+	}
+	if e.Pos > len(e.Expr) {
+		return utf8.RuneCountInString(e.Expr) + 1
+	}
+	return utf8.RuneCountInString(e.Expr[:e.Pos]) + 1
+}
+
+// Caret renders the expression with a caret under the error position.
+func (e *ExprError) Caret() string {
+	return e.Expr + "\n" + strings.Repeat(" ", e.Column()-1) + "^"
+}
+
+// exprFunctions lists the built-in functions and their arity.
+var exprFunctions = map[string]int{
+	"len":        1,
+	"contains":   2,
+	"matches":    2,
+	"startsWith": 2,
+	"endsWith":   2,
+}
+
+// exprNamespaces are the roots that may be referenced without ${}.
+var exprNamespaces = map[string]bool{
+	"vars":  true,
+	"steps": true,
+	"env":   true,
+	"loop":  true,
+}
+
+// Token types produced by the lexer.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokWord // identifier or unquoted bare word
+	tokRef  // ${...}
+	tokOp   // == != < <= > >= && || !
+	tokLParen
+	tokRParen
+	tokLBrack
+	tokRBrack
+	tokDot
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string // raw text (string literals are unquoted, refs are the inner path)
+	num  float64
+	pos  int // byte offset of the first character
+	end  int // byte offset just past the last character
+}
+
+// lexExpr splits an expression into tokens.
+func lexExpr(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			i++
+			continue
+		}
+		start := i
+		count := len(toks)
+		prevDot := count > 0 && toks[count-1].kind == tokDot
+
+		switch {
+		case c == '$' && i+1 < len(src) && src[i+1] == '{':
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, &ExprError{Expr: src, Pos: start, Message: "unterminated variable reference"}
+			}
+			inner := src[i+2 : i+end]
+			if strings.TrimSpace(inner) == "" {
+				return nil, &ExprError{Expr: src, Pos: start, Message: "empty variable reference"}
+			}
+			toks = append(toks, token{kind: tokRef, text: inner, pos: start})
+			i += end + 1
+
+		case c == '"' || c == '\'':
+			s, n, ok := scanString(src[i:])
+			if !ok {
+				return nil, &ExprError{Expr: src, Pos: start, Message: "unterminated string literal"}
+			}
+			toks = append(toks, token{kind: tokString, text: s, pos: start})
+			i += n
+
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: start})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: start})
+			i++
+		case c == '[':
+			toks = append(toks, token{kind: tokLBrack, text: "[", pos: start})
+			i++
+		case c == ']':
+			toks = append(toks, token{kind: tokRBrack, text: "]", pos: start})
+			i++
+		case c == ',':
+			toks = append(toks, token{kind: tokComma, text: ",", pos: start})
+			i++
+		case c == '.' && !(i+1 < len(src) && isDigit(src[i+1]) && !prevWordEnd(toks)):
+			toks = append(toks, token{kind: tokDot, text: ".", pos: start})
+			i++
+
+		case strings.HasPrefix(src[i:], "=="), strings.HasPrefix(src[i:], "!="),
+			strings.HasPrefix(src[i:], "<="), strings.HasPrefix(src[i:], ">="),
+			strings.HasPrefix(src[i:], "&&"), strings.HasPrefix(src[i:], "||"):
+			toks = append(toks, token{kind: tokOp, text: src[i : i+2], pos: start})
+			i += 2
+		case c == '<' || c == '>' || c == '!':
+			toks = append(toks, token{kind: tokOp, text: string(c), pos: start})
+			i++
+		case c == '=':
+			return nil, &ExprError{Expr: src, Pos: start, Message: "unexpected '=' (use '==' for equality)"}
+
+		case prevDot && isDigit(c):
+			// Index segment in a dotted path (vars.list.0): digits only.
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			n, _ := strconv.ParseFloat(src[start:i], 64)
+			toks = append(toks, token{kind: tokNumber, text: src[start:i], num: n, pos: start})
+
+		case isDigit(c) || c == '.' || (c == '-' && i+1 < len(src) && (isDigit(src[i+1]) || src[i+1] == '.')):
+			i++
+			for i < len(src) && isWordByte(src[i]) {
+				i++
+			}
+			text := src[start:i]
+			if n, err := strconv.ParseFloat(text, 64); err == nil {
+				toks = append(toks, token{kind: tokNumber, text: text, num: n, pos: start})
+			} else {
+				// Version strings and similar (1.2.3, 3rd) are bare words.
+				toks = append(toks, token{kind: tokWord, text: text, pos: start})
+			}
+
+		case isIdentStart(c):
+			for i < len(src) && isIdentByte(src[i]) {
+				i++
+			}
+			toks = append(toks, token{kind: tokWord, text: src[start:i], pos: start})
+
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			if unicode.IsLetter(r) {
+				for i < len(src) {
+					r, size := utf8.DecodeRuneInString(src[i:])
+					if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+						break
+					}
+					i += size
+				}
+				toks = append(toks, token{kind: tokWord, text: src[start:i], pos: start})
+				break
+			}
+			return nil, &ExprError{Expr: src, Pos: start, Message: fmt.Sprintf("unexpected character %q", r)}
+		}
+		if len(toks) > count {
+			toks[len(toks)-1].end = i
+		}
+	}
+	toks = append(toks, token{kind: tokEOF, pos: len(src), end: len(src)})
+	return toks, nil
+}
+
+// prevWordEnd reports whether the previous token can be followed by a member
+// access, in which case ".5" is a dot followed by an index rather than a number.
+func prevWordEnd(toks []token) bool {
+	if len(toks) == 0 {
+		return false
+	}
+	switch toks[len(toks)-1].kind {
+	case tokWord, tokRef, tokRParen, tokRBrack:
+		return true
+	}
+	return false
+}
+
+// scanString scans a quoted string literal at the start of s.
+// Returns the unescaped contents and the number of bytes consumed.
+func scanString(s string) (string, int, bool) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			next := s[i+1]
+			switch next {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '\\', '"', '\'':
+				b.WriteByte(next)
+			default:
+				b.WriteByte(c)
+				b.WriteByte(next)
+			}
+			i++
+			continue
+		}
+		if c == quote {
+			return b.String(), i + 1, true
+		}
+		b.WriteByte(c)
+	}
+	return "", 0, false
+}
+
+func isDigit(c byte) bool       { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool  { return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isIdentByte(c byte) bool   { return isIdentStart(c) || isDigit(c) || c == '-' }
+func isWordByte(c byte) bool    { return isIdentByte(c) || c == '.' }
+func isNullWord(w string) bool  { return w == "null" || w == "nil" }
+func isNamespace(w string) bool { return exprNamespaces[w] }
+
+// keywordOp maps word operators to their canonical form.
+func keywordOp(w string) string {
+	switch w {
+	case "AND", "and":
+		return "&&"
+	case "OR", "or":
+		return "||"
+	case "NOT", "not":
+		return "!"
+	case "contains", "matches", "in":
+		return w
+	}
+	return ""
+}
+
+// exprNode is a node of a parsed expression.
+type exprNode interface {
+	pos() int
+}
+
+type (
+	literalNode struct {
+		p     int
+		value interface{}
+		// interp marks string literals that may contain ${} references.
+		interp bool
+	}
+	refNode struct {
+		p          int
+		path       string
+		defaultVal string
+		hasDefault bool
+	}
+	listNode struct {
+		p     int
+		items []exprNode
+	}
+	unaryNode struct {
+		p       int
+		operand exprNode
+	}
+	binaryNode struct {
+		p           int
+		op          string
+		left, right exprNode
+	}
+	memberNode struct {
+		p      int
+		target exprNode
+		field  string
+	}
+	indexNode struct {
+		p      int
+		target exprNode
+		index  exprNode
+	}
+	callNode struct {
+		p    int
+		name string
+		args []exprNode
+	}
+)
+
+func (n *literalNode) pos() int { return n.p }
+func (n *refNode) pos() int     { return n.p }
+func (n *listNode) pos() int    { return n.p }
+func (n *unaryNode) pos() int   { return n.p }
+func (n *binaryNode) pos() int  { return n.p }
+func (n *memberNode) pos() int  { return n.p }
+func (n *indexNode) pos() int   { return n.p }
+func (n *callNode) pos() int    { return n.p }
+
+// Expr is a parsed condition expression.
+type Expr struct {
+	src  string
+	root exprNode
+}
+
+// String returns the source text of the expression.
+func (x *Expr) String() string {
+	return x.src
+}
+
+// ParseExpr parses a condition expression. Errors are *ExprError values
+// carrying the position of the offending token.
+func ParseExpr(src string) (*Expr, error) {
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{src: src, toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, p.errorf(p.peek(), "empty expression")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", describeToken(tok))
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+type exprParser struct {
+	src  string
+	toks []token
+	i    int
+}
+
+func (p *exprParser) peek() token { return p.toks[p.i] }
+
+func (p *exprParser) next() token {
+	tok := p.toks[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *exprParser) errorf(tok token, format string, args ...interface{}) error {
+	return &ExprError{Expr: p.src, Pos: tok.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// peekOp returns the canonical operator at the current position, if any.
+func (p *exprParser) peekOp() string {
+	tok := p.peek()
+	switch tok.kind {
+	case tokOp:
+		return tok.text
+	case tokWord:
+		return keywordOp(tok.text)
+	}
+	return ""
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp() == "||" {
+		tok := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{p: tok.pos, op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp() == "&&" {
+		tok := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{p: tok.pos, op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peekOp() == "!" {
+		tok := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{p: tok.pos, operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peekOp(); op {
+	case "==", "!=", "<", "<=", ">", ">=", "contains", "matches", "in":
+		tok := p.next()
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		node := &binaryNode{p: tok.pos, op: op, left: left, right: right}
+		if next := p.peekOp(); isComparisonOp(next) {
+			return nil, p.errorf(p.peek(), "comparison operators cannot be chained; use parentheses or '&&'")
+		}
+		if op == "matches" {
+			if err := checkRegexLiteral(p.src, right); err != nil {
+				return nil, err
+			}
+		}
+		return node, nil
+	}
+	return left, nil
+}
+
+func isComparisonOp(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "contains", "matches", "in":
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch tok := p.peek(); tok.kind {
+		case tokDot:
+			p.next()
+			field := p.next()
+			if field.kind != tokWord && field.kind != tokNumber {
+				return nil, p.errorf(field, "expected field name after '.', got %s", describeToken(field))
+			}
+			if field.kind == tokWord && p.peek().kind == tokLParen {
+				// Method call: x.startsWith("a") == startsWith(x, "a")
+				call, err := p.parseCall(field, node)
+				if err != nil {
+					return nil, err
+				}
+				node = call
+				continue
+			}
+			node = &memberNode{p: field.pos, target: node, field: field.text}
+		case tokLBrack:
+			p.next()
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if closing := p.next(); closing.kind != tokRBrack {
+				return nil, p.errorf(closing, "expected ']', got %s", describeToken(closing))
+			}
+			node = &indexNode{p: tok.pos, target: node, index: index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &literalNode{p: tok.pos, value: tok.num}, nil
+	case tokString:
+		return &literalNode{p: tok.pos, value: tok.text, interp: strings.Contains(tok.text, "${")}, nil
+	case tokRef:
+		path, def, hasDef := parseDefault(tok.text)
+		return &refNode{p: tok.pos, path: path, defaultVal: def, hasDefault: hasDef}, nil
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "expected ')', got %s", describeToken(closing))
+		}
+		return inner, nil
+	case tokLBrack:
+		list := &listNode{p: tok.pos}
+		if p.peek().kind == tokRBrack {
+			p.next()
+			return list, nil
+		}
+		for {
+			item, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			sep := p.next()
+			if sep.kind == tokRBrack {
+				return list, nil
+			}
+			if sep.kind != tokComma {
+				return nil, p.errorf(sep, "expected ',' or ']', got %s", describeToken(sep))
+			}
+		}
+	case tokWord:
+		switch {
+		case tok.text == "true":
+			return &literalNode{p: tok.pos, value: true}, nil
+		case tok.text == "false":
+			return &literalNode{p: tok.pos, value: false}, nil
+		case isNullWord(tok.text):
+			return &literalNode{p: tok.pos, value: nil}, nil
+		case p.peek().kind == tokLParen:
+			return p.parseCall(tok, nil)
+		case isNamespace(tok.text) && p.peek().kind == tokDot:
+			return p.parseNamespacePath(tok)
+		case keywordOp(tok.text) != "":
+			return nil, p.errorf(tok, "unexpected operator %q", tok.text)
+		}
+		// Unquoted bare words are legacy string literals; adjacent words
+		// join into one value so "${vars.msg} == hello world" still works.
+		end := tok.end
+		for p.isBareWord(p.i) {
+			end = p.next().end
+		}
+		return &literalNode{p: tok.pos, value: p.src[tok.pos:end]}, nil
+	case tokOp:
+		return nil, p.errorf(tok, "unexpected operator %q", tok.text)
+	case tokEOF:
+		return nil, p.errorf(tok, "unexpected end of expression")
+	}
+	return nil, p.errorf(tok, "unexpected %s", describeToken(tok))
+}
+
+// isBareWord reports whether the token at index i continues a run of
+// unquoted words.
+func (p *exprParser) isBareWord(i int) bool {
+	tok := p.toks[i]
+	if tok.kind != tokWord && tok.kind != tokNumber {
+		return false
+	}
+	if tok.kind == tokWord {
+		if keywordOp(tok.text) != "" || tok.text == "true" || tok.text == "false" || isNullWord(tok.text) {
+			return false
+		}
+		if isNamespace(tok.text) && p.toks[i+1].kind == tokDot {
+			return false
+		}
+	}
+	return p.toks[i+1].kind != tokLParen
+}
+
+// parseNamespacePath collects a dotted path such as vars.config.items.0 into
+// a reference, stopping before a method call.
+func (p *exprParser) parseNamespacePath(root token) (exprNode, error) {
+	parts := []string{root.text}
+	for p.peek().kind == tokDot {
+		// Stop before a method call so postfix parsing can handle it.
+		if p.i+2 < len(p.toks) && p.toks[p.i+1].kind == tokWord && p.toks[p.i+2].kind == tokLParen {
+			break
+		}
+		p.next()
+		seg := p.next()
+		if seg.kind != tokWord && seg.kind != tokNumber {
+			return nil, p.errorf(seg, "expected field name after '.', got %s", describeToken(seg))
+		}
+		parts = append(parts, seg.text)
+	}
+	return &refNode{p: root.pos, path: strings.Join(parts, ".")}, nil
+}
+
+// parseCall parses a function call whose name token has been consumed.
+// A non-nil receiver is passed as the first argument (method-call syntax).
+func (p *exprParser) parseCall(name token, receiver exprNode) (exprNode, error) {
+	arity, ok := exprFunctions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %q (available: %s)", name.text, strings.Join(sortedFunctionNames(), ", "))
+	}
+	p.next() // (
+	call := &callNode{p: name.pos, name: name.text}
+	if receiver != nil {
+		call.args = append(call.args, receiver)
+	}
+	if p.peek().kind == tokRParen {
+		p.next()
+	} else {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			sep := p.next()
+			if sep.kind == tokRParen {
+				break
+			}
+			if sep.kind != tokComma {
+				return nil, p.errorf(sep, "expected ',' or ')', got %s", describeToken(sep))
+			}
+		}
+	}
+	if len(call.args) != arity {
+		return nil, p.errorf(name, "%s expects %d argument(s), got %d", name.text, arity, len(call.args))
+	}
+	if name.text == "matches" {
+		if err := checkRegexLiteral(p.src, call.args[1]); err != nil {
+			return nil, err
+		}
+	}
+	return call, nil
+}
+
+// checkRegexLiteral validates a literal regex pattern at parse time.
+func checkRegexLiteral(src string, node exprNode) error {
+	lit, ok := node.(*literalNode)
+	if !ok || lit.interp {
+		return nil
+	}
+	pattern, ok := lit.value.(string)
+	if !ok {
+		return nil
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return &ExprError{Expr: src, Pos: lit.p, Message: fmt.Sprintf("invalid regular expression: %v", err)}
+	}
+	return nil
+}
+
+func sortedFunctionNames() []string {
+	names := make([]string, 0, len(exprFunctions))
+	for name := range exprFunctions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func describeToken(tok token) string {
+	switch tok.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return fmt.Sprintf("string %q", tok.text)
+	case tokRef:
+		return fmt.Sprintf("reference ${%s}", tok.text)
+	}
+	return fmt.Sprintf("%q", tok.text)
+}
+
+// Eval evaluates the expression against the substitutor's execution state.
+func (x *Expr) Eval(sub *Substitutor) (interface{}, error) {
+	ev := &exprEvaluator{src: x.src, sub: sub}
+	return ev.eval(x.root)
+}
+
+// EvalBool evaluates the expression and converts the result to a boolean
+// using the truthiness rules of the language.
+func (x *Expr) EvalBool(sub *Substitutor) (bool, error) {
+	v, err := x.Eval(sub)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+type exprEvaluator struct {
+	src string
+	sub *Substitutor
+}
+
+func (ev *exprEvaluator) errorf(n exprNode, format string, args ...interface{}) error {
+	return &ExprError{Expr: ev.src, Pos: n.pos(), Message: fmt.Sprintf(format, args...)}
+}
+
+func (ev *exprEvaluator) eval(n exprNode) (interface{}, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		if n.interp && ev.sub != nil {
+			s, err := ev.sub.Substitute(n.value.(string))
+			if err != nil {
+				return nil, ev.errorf(n, "%v", err)
+			}
+			return s, nil
+		}
+		return n.value, nil
+
+	case *refNode:
+		if ev.sub == nil {
+			return nil, ev.errorf(n, "cannot resolve %s: no execution context", n.path)
+		}
+		v, err := ev.sub.resolveVar(n.path)
+		if err != nil {
+			if n.hasDefault {
+				return n.defaultVal, nil
+			}
+			return nil, ev.errorf(n, "variable substitution failed for '%s': %v", n.path, err)
+		}
+		return normalizeValue(v), nil
+
+	case *listNode:
+		items := make([]interface{}, 0, len(n.items))
+		for _, item := range n.items {
+			v, err := ev.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+
+	case *unaryNode:
+		v, err := ev.eval(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		return !truthy(v), nil
+
+	case *binaryNode:
+		return ev.evalBinary(n)
+
+	case *memberNode:
+		target, err := ev.eval(n.target)
+		if err != nil {
+			return nil, err
+		}
+		v, err := navigateNested(target, []string{n.field})
+		if err != nil {
+			return nil, ev.errorf(n, "%v", err)
+		}
+		return normalizeValue(v), nil
+
+	case *indexNode:
+		target, err := ev.eval(n.target)
+		if err != nil {
+			return nil, err
+		}
+		index, err := ev.eval(n.index)
+		if err != nil {
+			return nil, err
+		}
+		v, err := navigateNested(target, []string{formatValue(index)})
+		if err != nil {
+			return nil, ev.errorf(n.index, "%v", err)
+		}
+		return normalizeValue(v), nil
+
+	case *callNode:
+		return ev.evalCall(n)
+	}
+	return nil, fmt.Errorf("unknown expression node %T", n)
+}
+
+func (ev *exprEvaluator) evalBinary(n *binaryNode) (interface{}, error) {
+	left, err := ev.eval(n.left)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short-circuit.
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := ev.eval(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := ev.eval(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	}
+
+	right, err := ev.eval(n.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		cmp, err := compareValues(left, right)
+		if err != nil {
+			return nil, ev.errorf(n, "cannot compare: %v", err)
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "contains":
+		return containsValue(left, right), nil
+	case "in":
+		return containsValue(right, left), nil
+	case "matches":
+		return ev.matchValue(n.right, left, right)
+	}
+	return nil, ev.errorf(n, "unknown operator %q", n.op)
+}
+
+func (ev *exprEvaluator) evalCall(n *callNode) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := ev.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	switch n.name {
+	case "len":
+		switch v := args[0].(type) {
+		case nil:
+			return float64(0), nil
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, ev.errorf(n, "len: unsupported type %s", typeName(args[0]))
+	case "contains":
+		return containsValue(args[0], args[1]), nil
+	case "matches":
+		return ev.matchValue(n.args[1], args[0], args[1])
+	case "startsWith":
+		return strings.HasPrefix(formatValue(args[0]), formatValue(args[1])), nil
+	case "endsWith":
+		return strings.HasSuffix(formatValue(args[0]), formatValue(args[1])), nil
+	}
+	return nil, ev.errorf(n, "unknown function %q", n.name)
+}
+
+func (ev *exprEvaluator) matchValue(patternNode exprNode, subject, pattern interface{}) (interface{}, error) {
+	re, err := regexp.Compile(formatValue(pattern))
+	if err != nil {
+		return nil, ev.errorf(patternNode, "invalid regular expression: %v", err)
+	}
+	return re.MatchString(formatValue(subject)), nil
+}
+
+// normalizeValue converts Go values into the expression type system:
+// nil, bool, float64, string, []interface{} and map[string]interface{}.
+func normalizeValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, float64, string, []interface{}, map[string]interface{}:
+		return v
+	case int:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case uint:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case []string:
+		out := make([]interface{}, len(x))
+		for i, s := range x {
+			out[i] = s
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			out[fmt.Sprintf("%v", k)] = val
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = rv.Index(i).Interface()
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[fmt.Sprintf("%v", iter.Key().Interface())] = iter.Value().Interface()
+		}
+		return out
+	}
+	return formatValue(v)
+}
+
+// truthy converts a value to a boolean. Strings use the legacy falsy set
+// ("", "false", "0", "no", "null", "nil", "none", "undefined").
+func truthy(v interface{}) bool {
+	switch x := normalizeValue(v).(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		switch strings.ToLower(strings.TrimSpace(x)) {
+		case "", "false", "0", "no", "null", "nil", "none", "undefined":
+			return false
+		}
+		return true
+	case []interface{}:
+		return len(x) > 0
+	case map[string]interface{}:
+		return len(x) > 0
+	}
+	return true
+}
+
+// asNumber converts numbers and numeric strings to float64.
+func asNumber(v interface{}) (float64, bool) {
+	switch x := normalizeValue(v).(type) {
+	case float64:
+		return x, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// valuesEqual compares two values: numerically when both are numeric,
+// structurally for lists and maps, and by string form otherwise.
+func valuesEqual(a, b interface{}) bool {
+	a, b = normalizeValue(a), normalizeValue(b)
+	if a == nil || b == nil {
+		return (a == nil && b == nil) || formatValue(a) == formatValue(b)
+	}
+	if an, ok := asNumber(a); ok {
+		if bn, ok := asNumber(b); ok {
+			return an == bn
+		}
+	}
+	switch a.(type) {
+	case []interface{}, map[string]interface{}:
+		return reflect.DeepEqual(a, b)
+	}
+	switch b.(type) {
+	case []interface{}, map[string]interface{}:
+		return false
+	}
+	return formatValue(a) == formatValue(b)
+}
+
+// compareValues orders two values. Numbers (and numeric strings) compare
+// numerically, other strings lexically; anything else is an error.
+func compareValues(a, b interface{}) (int, error) {
+	an, aNum := asNumber(a)
+	bn, bNum := asNumber(b)
+	switch {
+	case aNum && bNum:
+		switch {
+		case an < bn:
+			return -1, nil
+		case an > bn:
+			return 1, nil
+		}
+		return 0, nil
+	case !aNum && bNum:
+		return 0, fmt.Errorf("left operand '%s' is not a number", formatValue(a))
+	case aNum && !bNum:
+		return 0, fmt.Errorf("right operand '%s' is not a number", formatValue(b))
+	}
+	as, aStr := normalizeValue(a).(string)
+	bs, bStr := normalizeValue(b).(string)
+	if !aStr || !bStr {
+		return 0, fmt.Errorf("cannot order %s and %s", typeName(a), typeName(b))
+	}
+	return strings.Compare(as, bs), nil
+}
+
+// containsValue reports whether container holds needle: list membership,
+// map key presence, or substring match for everything else.
+func containsValue(container, needle interface{}) bool {
+	switch c := normalizeValue(container).(type) {
+	case []interface{}:
+		for _, item := range c {
+			if valuesEqual(item, needle) {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		_, ok := c[formatValue(needle)]
+		return ok
+	case nil:
+		return false
+	}
+	return strings.Contains(formatValue(container), formatValue(needle))
+}
+
+func typeName(v interface{}) string {
+	switch normalizeValue(v).(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
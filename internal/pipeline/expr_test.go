@@ -0,0 +1,239 @@
+package pipeline
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newExprTestSubstitutor() *Substitutor {
+	state := &ExecutionState{
+		Variables: map[string]interface{}{
+			"env":      "production",
+			"msg":      "hello world",
+			"tricky":   `a == "b" AND c`,
+			"version":  "1.2.3",
+			"count":    3,
+			"packages": []interface{}{"api", "cli", "web"},
+			"config": map[string]interface{}{
+				"retries": 2,
+				"tags":    []interface{}{"fast", "safe"},
+			},
+		},
+		Steps: map[string]StepResult{
+			"review": {
+				StepID: "review",
+				Status: StatusCompleted,
+				Output: `{"approved": true, "score": 0.92, "issues": [{"severity": "low"}], "summary": "LGTM"}`,
+				ParsedData: map[string]interface{}{
+					"approved": true,
+					"score":    0.92,
+					"issues": []interface{}{
+						map[string]interface{}{"severity": "low"},
+					},
+					"summary": "LGTM",
+				},
+			},
+		},
+	}
+	return NewSubstitutor(state, "test-session", "test-workflow")
+}
+
+func TestParseExpr_Evaluate(t *testing.T) {
+	sub := newExprTestSubstitutor()
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// Symbolic logical operators and precedence
+		{`${vars.env} == "production" && ${vars.count} > 2`, true},
+		{`${vars.env} == "staging" || ${vars.count} == 3`, true},
+		{`!(${vars.env} == "production")`, false},
+		{`false && true || true`, true},
+		{`false && (true || true)`, false},
+		{`(true || false) && false`, false},
+
+		// Values containing operators are data, not syntax
+		{`${vars.tricky} == 'a == "b" AND c'`, true},
+		{`${vars.tricky} contains "AND"`, true},
+		{`"x || y" == "x || y"`, true},
+
+		// Typed access into parsed JSON output
+		{`${steps.review.data.approved}`, true},
+		{`${steps.review.data.approved} == true`, true},
+		{`${steps.review.data.score} >= 0.9`, true},
+		{`steps.review.data.issues[0].severity == "low"`, true},
+		{`steps.review.data.issues.0.severity == "low"`, true},
+		{`${steps.review.data}["summary"] == "LGTM"`, true},
+		{`vars.config.retries < 3`, true},
+		{`vars.config["tags"][1] == "safe"`, true},
+
+		// Functions and method calls
+		{`len(vars.packages) == 3`, true},
+		{`len(${steps.review.data.issues}) > 0`, true},
+		{`len("") == 0`, true},
+		{`contains(vars.packages, "cli")`, true},
+		{`contains(vars.packages, "db")`, false},
+		{`vars.config.tags.contains("fast")`, true},
+		{`matches(${vars.version}, "^1\\.[0-9]+\\.[0-9]+$")`, true},
+		{`${vars.version} matches "^2\\."`, false},
+		{`startsWith(${vars.env}, "prod")`, true},
+		{`${vars.env}.startsWith("stag")`, false},
+		{`endsWith(${vars.msg}, "world")`, true},
+
+		// Membership
+		{`"web" in vars.packages`, true},
+		{`${vars.env} in ["staging", "production"]`, true},
+		{`"retries" in vars.config`, true},
+
+		// Legacy bare words
+		{`${vars.env} == production`, true},
+		{`${vars.msg} == hello world`, true},
+		{`${vars.version} == 1.2.3`, true},
+		{`${vars.count} == "3"`, true},
+
+		// Defaults
+		{`${vars.missing | "fallback"} == "fallback"`, true},
+		{`${vars.missing | "0"}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseExpr(%q) error = %v", tt.expr, err)
+			}
+			got, err := expr.EvalBool(sub)
+			if err != nil {
+				t.Fatalf("EvalBool(%q) error = %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("EvalBool(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExpr_SyntaxErrorPositions(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantCol int
+		wantMsg string
+	}{
+		{`${vars.x} == `, 14, "unexpected end of expression"},
+		{`(${vars.x} == 1`, 16, "expected ')'"},
+		{`${vars.x} == 1)`, 15, `unexpected ")"`},
+		{`${vars.x} = 1`, 11, "use '=='"},
+		{`"unclosed`, 1, "unterminated string"},
+		{`${vars.x`, 1, "unterminated variable reference"},
+		{`size(vars.x) > 1`, 1, `unknown function "size"`},
+		{`len(vars.a, vars.b) > 1`, 1, "len expects 1 argument(s), got 2"},
+		{`1 < 2 < 3`, 7, "cannot be chained"},
+		{`matches(vars.x, "[")`, 17, "invalid regular expression"},
+		{`vars.x && || vars.y`, 11, `unexpected operator "||"`},
+		{`vars.x.`, 8, "expected field name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseExpr(tt.expr)
+			if err == nil {
+				t.Fatalf("ParseExpr(%q) expected error", tt.expr)
+			}
+			var exprErr *ExprError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("ParseExpr(%q) error type = %T, want *ExprError", tt.expr, err)
+			}
+			if exprErr.Column() != tt.wantCol {
+				t.Errorf("ParseExpr(%q) column = %d, want %d (%v)", tt.expr, exprErr.Column(), tt.wantCol, err)
+			}
+			if !strings.Contains(exprErr.Message, tt.wantMsg) {
+				t.Errorf("ParseExpr(%q) message = %q, want substring %q", tt.expr, exprErr.Message, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestParseExpr_EvalErrors(t *testing.T) {
+	sub := newExprTestSubstitutor()
+
+	tests := []struct {
+		expr    string
+		wantCol int
+	}{
+		{`${vars.undefined} == 1`, 1},
+		{`vars.config.missing == 1`, 1},
+		{`vars.packages[5] == "x"`, 15},
+		{`${vars.env} > 3`, 13},
+		{`len(true) == 0`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseExpr(%q) error = %v", tt.expr, err)
+			}
+			_, err = expr.Eval(sub)
+			var exprErr *ExprError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("Eval(%q) error = %v, want *ExprError", tt.expr, err)
+			}
+			if exprErr.Column() != tt.wantCol {
+				t.Errorf("Eval(%q) column = %d, want %d (%v)", tt.expr, exprErr.Column(), tt.wantCol, err)
+			}
+		})
+	}
+}
+
+func TestExprError_Caret(t *testing.T) {
+	err := &ExprError{Expr: "a == ", Pos: 5, Message: "unexpected end of expression"}
+	want := "a == \n     ^"
+	if got := err.Caret(); got != want {
+		t.Errorf("Caret() = %q, want %q", got, want)
+	}
+	if got := err.Error(); got != "column 6: unexpected end of expression" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestValidate_InvalidWhenExpression(t *testing.T) {
+	w := &Workflow{
+		SchemaVersion: SchemaVersion,
+		Name:          "test",
+		Steps: []Step{
+			{ID: "a", Prompt: "x", When: `${vars.x} == "y" &&`},
+			{ID: "b", Prompt: "y", Loop: &LoopConfig{Items: "${vars.list}", While: "(true"}},
+		},
+	}
+
+	result := Validate(w)
+	if result.Valid {
+		t.Fatal("expected validation to fail")
+	}
+
+	byField := make(map[string]ParseError)
+	for _, e := range result.Errors {
+		byField[e.Field] = e
+	}
+
+	when, ok := byField["steps[0].when"]
+	if !ok {
+		t.Fatalf("missing error for steps[0].when: %v", result.Errors)
+	}
+	if when.Column != 20 {
+		t.Errorf("when column = %d, want 20", when.Column)
+	}
+	if !strings.Contains(when.Error(), "steps[0].when:col 20") {
+		t.Errorf("when Error() = %q, want location with column", when.Error())
+	}
+
+	while, ok := byField["steps[1].loop.while"]
+	if !ok {
+		t.Fatalf("missing error for steps[1].loop.while: %v", result.Errors)
+	}
+	if while.Column != 6 {
+		t.Errorf("while column = %d, want 6", while.Column)
+	}
+}
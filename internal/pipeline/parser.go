@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 type ParseError struct {
 	File    string `json:"file,omitempty"`
 	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"` // Column within an expression field (when, while)
 	Field   string `json:"field,omitempty"`
 	Message string `json:"message"`
 	Hint    string `json:"hint,omitempty"`
@@ -30,6 +32,9 @@ func (e ParseError) Error() string {
 	if e.Field != "" {
 		parts = append(parts, e.Field)
 	}
+	if e.Column > 0 {
+		parts = append(parts, fmt.Sprintf("col %d", e.Column))
+	}
 
 	location := strings.Join(parts, ":")
 	if location != "" {
@@ -298,6 +303,11 @@ func validateStep(step *Step, stepField string, stepIDs map[string]bool, result
 		})
 	}
 
+	// Validate condition expression
+	if step.When != "" {
+		validateExpression(step.When, stepField+".when", result)
+	}
+
 	// Validate parallel sub-steps
 	for j, pStep := range step.Parallel {
 		validateStep(&pStep, fmt.Sprintf("%s.parallel[%d]", stepField, j), stepIDs, result)
@@ -312,6 +322,9 @@ func validateStep(step *Step, stepField string, stepIDs map[string]bool, result
 				Hint:    "Specify the variable to iterate over",
 			})
 		}
+		if step.Loop.While != "" {
+			validateExpression(step.Loop.While, stepField+".loop.while", result)
+		}
 		if step.Loop.MaxIterations < 0 {
 			result.addError(ParseError{
 				Field:   stepField + ".loop.max_iterations",
@@ -324,6 +337,26 @@ func validateStep(step *Step, stepField string, stepIDs map[string]bool, result
 	}
 }
 
+// validateExpression parses a condition expression and reports syntax errors
+// with the column of the offending token.
+func validateExpression(expr, field string, result *ValidationResult) {
+	_, err := ParseExpr(expr)
+	if err == nil {
+		return
+	}
+	pe := ParseError{
+		Field:   field,
+		Message: fmt.Sprintf("invalid expression: %v", err),
+	}
+	var exprErr *ExprError
+	if errors.As(err, &exprErr) {
+		pe.Column = exprErr.Column()
+		pe.Message = fmt.Sprintf("invalid expression: %s", exprErr.Message)
+		pe.Hint = exprErr.Caret()
+	}
+	result.addError(pe)
+}
+
 // detectCycles finds circular dependencies in steps
 func detectCycles(steps []Step) [][]string {
 	// Build dependency graph